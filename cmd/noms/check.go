@@ -0,0 +1,44 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/attic-labs/noms/go/check"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// runCheck verifies the integrity of every chunk reachable from roots in
+// vs, printing problems as they're found. It returns false if any problems
+// were found.
+func runCheck(vs *types.ValueStore, roots ...hash.Hash) bool {
+	c := check.NewChecker(vs, roots...)
+	ctx := context.Background()
+	ok := true
+
+	for _, pass := range []struct {
+		name string
+		run  func(context.Context, chan<- error)
+	}{
+		{"Packs", c.Packs},
+		{"ReadData", c.ReadData},
+		{"Structure", c.Structure},
+	} {
+		errCh := make(chan error)
+		go func(run func(context.Context, chan<- error)) {
+			run(ctx, errCh)
+			close(errCh)
+		}(pass.run)
+		for err := range errCh {
+			ok = false
+			fmt.Fprintf(os.Stderr, "%s: %s\n", pass.name, err)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,30 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+// codecFlagDefault is the default value for a --codec flag.
+const codecFlagDefault = "snappy"
+
+// parseCodec resolves a --codec flag value ("snappy", "xz" or "none") to the
+// types.ChunkCodec it names. No command in this package registers a --codec
+// flag or calls parseCodec.
+func parseCodec(name string) (types.ChunkCodec, error) {
+	switch name {
+	case "snappy":
+		return types.SnappyCodec, nil
+	case "xz":
+		return types.XZCodec, nil
+	case "none":
+		return types.IdentityCodec, nil
+	default:
+		return nil, fmt.Errorf("unknown --codec %q: want one of snappy, xz, none", name)
+	}
+}
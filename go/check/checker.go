@@ -0,0 +1,154 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package check implements integrity checking for a noms database, in the
+// spirit of restic's checker: given a set of root hashes, walk everything
+// reachable from them and report problems on a channel instead of failing
+// fast, so a caller (e.g. the noms CLI) can surface every problem found in
+// a single pass.
+package check
+
+import (
+	"context"
+
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// Checker verifies the integrity of the chunks reachable from a set of
+// roots in a types.ValueStore.
+type Checker struct {
+	vs    *types.ValueStore
+	roots hash.HashSlice
+}
+
+// NewChecker returns a Checker that will verify everything reachable from
+// roots in vs.
+func NewChecker(vs *types.ValueStore, roots ...hash.Hash) *Checker {
+	return &Checker{vs, roots}
+}
+
+// Packs verifies that every chunk reachable from c's roots is present in
+// the underlying ChunkStore, checking presence with ChunkStore.Has rather
+// than fetching the chunk; the traversal itself still reads and decodes
+// each present chunk to find its children. It's the cheapest of the three
+// passes, analogous to restic's Packs() check that every referenced pack
+// file exists.
+func (c *Checker) Packs(ctx context.Context, errCh chan<- error) {
+	cs := c.vs.ChunkStore()
+	c.walk(ctx, errCh, func(h, ref hash.Hash) {
+		if !cs.Has(h) {
+			errCh <- ErrMissingChunk{Ref: ref, Target: h}
+		}
+	})
+}
+
+// ReadData reads and decodes every chunk reachable from c's roots (undoing
+// whatever types.ChunkCodec it was written with) and recomputes its Value's
+// hash, reporting an ErrHashMismatch for any chunk whose stored hash
+// doesn't match. Values in this database aren't content-addressed by their
+// raw on-disk bytes -- Value.Hash() is computed over the decoded Value
+// itself -- so unlike restic's analogous pack check, this can't just hash
+// cs.Get's bytes; it has to decode first.
+func (c *Checker) ReadData(ctx context.Context, errCh chan<- error) {
+	cs := c.vs.ChunkStore()
+	c.walk(ctx, errCh, func(h, ref hash.Hash) {
+		chunk := cs.Get(h)
+		if chunk.IsEmpty() {
+			errCh <- ErrMissingChunk{Ref: ref, Target: h}
+			return
+		}
+		v, err := types.DecodeChunk(chunk)
+		if err != nil {
+			errCh <- ErrHashMismatch{Stored: h}
+			return
+		}
+		if computed := v.Hash(); computed != h {
+			errCh <- ErrHashMismatch{Stored: h, Computed: computed}
+		}
+	})
+}
+
+// Structure decodes every chunk reachable from c's roots and verifies the
+// invariants declared on each types.Ref it contains -- that the Ref's
+// target exists, and that the Ref's declared Height and TargetType agree
+// with the chunk it points at.
+func (c *Checker) Structure(ctx context.Context, errCh chan<- error) {
+	roots := hash.HashSet{}
+	for _, r := range c.roots {
+		roots.Insert(r)
+	}
+	c.walk(ctx, errCh, func(h, _ hash.Hash) {
+		v := c.vs.ReadValue(h)
+		if v == nil {
+			// A non-root hash only ever reaches walk's queue because some
+			// other Value's WalkRefs below pointed at it; that Ref's own
+			// ErrMissingChunk (with Ref context) already covers it, so
+			// reporting again here would double up. A missing root, on
+			// the other hand, is nobody's Ref target and needs this as
+			// its only chance to be reported.
+			if roots.Has(h) {
+				errCh <- ErrMissingChunk{Target: h}
+			}
+			return
+		}
+		v.WalkRefs(func(r types.Ref) {
+			target := c.vs.ReadValue(r.TargetHash())
+			if target == nil {
+				errCh <- ErrMissingChunk{Ref: h, Target: r.TargetHash()}
+				return
+			}
+			if actual := types.NewRef(target); actual.Height() != r.Height() {
+				errCh <- ErrRefHeightMismatch{Target: r.TargetHash(), Declared: r.Height(), Actual: actual.Height()}
+			} else if !actual.TargetType().Equals(r.TargetType()) {
+				errCh <- ErrRefTypeMismatch{Target: r.TargetHash(), Declared: r.TargetType().String(), Actual: actual.TargetType().String()}
+			}
+		})
+	})
+}
+
+// walkEntry is a hash queued for walk to visit, along with the hash of the
+// Ref that discovered it (the zero hash.Hash for one of c's roots).
+type walkEntry struct {
+	h   hash.Hash
+	ref hash.Hash
+}
+
+// walk performs a breadth-first traversal of every hash reachable from c's
+// roots, invoking visit exactly once per reachable hash with the hash of
+// the Ref that discovered it (the zero hash.Hash for a root), so callers
+// can report which chunk referenced a problem target. It stops early if
+// ctx is cancelled.
+func (c *Checker) walk(ctx context.Context, errCh chan<- error, visit func(h, ref hash.Hash)) {
+	seen := hash.HashSet{}
+	queue := make([]walkEntry, len(c.roots))
+	for i, r := range c.roots {
+		queue[i] = walkEntry{h: r}
+	}
+	for len(queue) > 0 {
+		select {
+		case <-ctx.Done():
+			errCh <- ctx.Err()
+			return
+		default:
+		}
+
+		e := queue[0]
+		queue = queue[1:]
+		if seen.Has(e.h) {
+			continue
+		}
+		seen.Insert(e.h)
+
+		visit(e.h, e.ref)
+
+		if v := c.vs.ReadValue(e.h); v != nil {
+			v.WalkRefs(func(r types.Ref) {
+				if !seen.Has(r.TargetHash()) {
+					queue = append(queue, walkEntry{h: r.TargetHash(), ref: e.h})
+				}
+			})
+		}
+	}
+}
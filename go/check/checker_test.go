@@ -0,0 +1,146 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package check
+
+import (
+	"context"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/testify/assert"
+)
+
+func drain(errCh chan error) []error {
+	var errs []error
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+func TestCheckerCleanDatabase(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := types.NewValueStore(cs)
+
+	l := types.NewList(vs.WriteValue(types.String("a")), vs.WriteValue(types.Number(1)))
+	r := vs.WriteValue(l)
+	vs.Flush()
+
+	c := NewChecker(vs, r.TargetHash())
+
+	for _, pass := range []func(context.Context, chan<- error){c.Packs, c.ReadData, c.Structure} {
+		errCh := make(chan error)
+		go func(pass func(context.Context, chan<- error)) {
+			pass(context.Background(), errCh)
+			close(errCh)
+		}(pass)
+		assert.Empty(drain(errCh))
+	}
+}
+
+func TestCheckerReadDataHashMismatch(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := types.NewValueStore(cs)
+
+	r := vs.WriteValue(types.String("hello"))
+	vs.Flush()
+
+	// Simulate on-disk corruption: same hash, different bytes.
+	cs.Put(chunks.NewChunkWithHash(r.TargetHash(), []byte("corrupted")))
+
+	c := NewChecker(vs, r.TargetHash())
+	errCh := make(chan error)
+	go func() { c.ReadData(context.Background(), errCh); close(errCh) }()
+
+	errs := drain(errCh)
+	if assert.Len(errs, 1) {
+		_, ok := errs[0].(ErrHashMismatch)
+		assert.True(ok, "expected ErrHashMismatch, got %T", errs[0])
+	}
+}
+
+func TestCheckerReadDataHashMismatchValidlyEncodedWrongContent(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := types.NewValueStore(cs)
+
+	r := vs.WriteValue(types.String("hello"))
+	other := vs.WriteValue(types.String("goodbye"))
+	vs.Flush()
+
+	// Swap in a validly codec-wrapped chunk for a *different* Value, so
+	// decoding succeeds but the decoded Value's hash doesn't match the key
+	// it was stored under -- this must go through the decode-then-compare
+	// path, not fail to decode at all.
+	swapped := cs.Get(other.TargetHash())
+	cs.Put(chunks.NewChunkWithHash(r.TargetHash(), swapped.Data()))
+
+	c := NewChecker(vs, r.TargetHash())
+	errCh := make(chan error)
+	go func() { c.ReadData(context.Background(), errCh); close(errCh) }()
+
+	errs := drain(errCh)
+	if assert.Len(errs, 1) {
+		mismatch, ok := errs[0].(ErrHashMismatch)
+		assert.True(ok, "expected ErrHashMismatch, got %T", errs[0])
+		assert.Equal(other.TargetHash(), mismatch.Computed)
+	}
+}
+
+func TestCheckerPacksAndReadDataReportReferrerOfMissingChunk(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := types.NewValueStore(cs)
+
+	// orphanRef points at a Value that was never written, simulating a
+	// chunk that's gone missing from the store.
+	orphanRef := types.NewRef(types.String("never written"))
+	l := types.NewList(orphanRef)
+	lr := vs.WriteValue(l)
+	vs.Flush()
+
+	for _, pass := range []func(context.Context, chan<- error){NewChecker(vs, lr.TargetHash()).Packs, NewChecker(vs, lr.TargetHash()).ReadData} {
+		errCh := make(chan error)
+		go func(pass func(context.Context, chan<- error)) {
+			pass(context.Background(), errCh)
+			close(errCh)
+		}(pass)
+
+		errs := drain(errCh)
+		if assert.Len(errs, 1) {
+			missing, ok := errs[0].(ErrMissingChunk)
+			assert.True(ok, "expected ErrMissingChunk, got %T", errs[0])
+			assert.Equal(orphanRef.TargetHash(), missing.Target)
+			assert.Equal(lr.TargetHash(), missing.Ref, "Ref should name the parent that pointed at the missing chunk, like Structure already does")
+		}
+	}
+}
+
+func TestCheckerStructureMissingChunk(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := types.NewValueStore(cs)
+
+	// orphanRef points at a Value that was never written, simulating a
+	// chunk that's gone missing from the store.
+	orphanRef := types.NewRef(types.String("never written"))
+	l := types.NewList(orphanRef)
+	lr := vs.WriteValue(l)
+	vs.Flush()
+
+	c := NewChecker(vs, lr.TargetHash())
+	errCh := make(chan error)
+	go func() { c.Structure(context.Background(), errCh); close(errCh) }()
+
+	errs := drain(errCh)
+	if assert.Len(errs, 1) {
+		missing, ok := errs[0].(ErrMissingChunk)
+		assert.True(ok, "expected ErrMissingChunk, got %T", errs[0])
+		assert.Equal(orphanRef.TargetHash(), missing.Target)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package check
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// ErrMissingChunk is reported when a Ref points at a hash that the
+// ChunkStore does not have.
+type ErrMissingChunk struct {
+	// Ref is the hash of the Value that referenced the missing chunk, or the
+	// zero hash.Hash if the missing chunk is one of the Checker's roots.
+	Ref hash.Hash
+	// Target is the missing chunk's hash.
+	Target hash.Hash
+}
+
+func (e ErrMissingChunk) Error() string {
+	return fmt.Sprintf("%s: missing chunk %s", e.Ref, e.Target)
+}
+
+// ErrHashMismatch is reported when a chunk's recomputed hash doesn't match
+// the hash it was stored under.
+type ErrHashMismatch struct {
+	// Stored is the hash the chunk was read from the ChunkStore under.
+	Stored hash.Hash
+	// Computed is the hash of the Value decoded from the chunk, or the zero
+	// hash.Hash if the chunk could not be decoded.
+	Computed hash.Hash
+}
+
+func (e ErrHashMismatch) Error() string {
+	if e.Computed.IsEmpty() {
+		return fmt.Sprintf("chunk stored as %s could not be decoded", e.Stored)
+	}
+	return fmt.Sprintf("chunk stored as %s actually hashes to %s", e.Stored, e.Computed)
+}
+
+// ErrRefHeightMismatch is reported when a Ref's declared Height doesn't
+// match the height recomputed from the chunk it targets.
+type ErrRefHeightMismatch struct {
+	Target   hash.Hash
+	Declared uint64
+	Actual   uint64
+}
+
+func (e ErrRefHeightMismatch) Error() string {
+	return fmt.Sprintf("ref to %s declares height %d, but actual height is %d", e.Target, e.Declared, e.Actual)
+}
+
+// ErrRefTypeMismatch is reported when a Ref's declared TargetType doesn't
+// match the Type of the chunk it targets.
+type ErrRefTypeMismatch struct {
+	Target   hash.Hash
+	Declared string
+	Actual   string
+}
+
+func (e ErrRefTypeMismatch) Error() string {
+	return fmt.Sprintf("ref to %s declares type %s, but actual type is %s", e.Target, e.Declared, e.Actual)
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import "github.com/attic-labs/noms/go/hash"
+
+// Chunk is a list of bytes with a computed hash, used as the unit of storage
+// and transmission for noms databases.
+type Chunk struct {
+	r    hash.Hash
+	data []byte
+}
+
+// EmptyChunk is the Chunk for an empty byte slice.
+var EmptyChunk = NewChunk([]byte{})
+
+// Hash returns the hash c was created with: hash.Of(data) for NewChunk, or
+// whatever was passed to NewChunkWithHash.
+func (c Chunk) Hash() hash.Hash {
+	return c.r
+}
+
+// Data returns the bytes of the Chunk.
+func (c Chunk) Data() []byte {
+	return c.data
+}
+
+// IsEmpty returns true if c has no data.
+func (c Chunk) IsEmpty() bool {
+	return len(c.data) == 0
+}
+
+// NewChunk computes the hash of data and returns a new Chunk wrapping it.
+func NewChunk(data []byte) Chunk {
+	r := hash.Of(data)
+	return NewChunkWithHash(r, data)
+}
+
+// NewChunkWithHash returns a new Chunk wrapping data under hash r. r is not
+// checked against data.
+func NewChunkWithHash(r hash.Hash, data []byte) Chunk {
+	return Chunk{r, data}
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import "github.com/attic-labs/noms/go/hash"
+
+// ChunkStore is the interface used by higher layers (types.ValueStore) to
+// durably persist and retrieve Chunks. Implementations must be safe for
+// concurrent use.
+type ChunkStore interface {
+	// Get returns the Chunk for h, or an empty Chunk if none is present.
+	Get(h hash.Hash) Chunk
+
+	// GetMany gets the Chunks with the given hashes, streaming each found
+	// Chunk to foundChunks as it's retrieved.
+	GetMany(hashes hash.HashSet, foundChunks chan *Chunk)
+
+	// Has returns true if the Chunk for h is present.
+	Has(h hash.Hash) bool
+
+	// HasMany returns the subset of hashes that are NOT present.
+	HasMany(hashes hash.HashSet) (absent hash.HashSet)
+
+	// Put caches c for later flushing to the underlying store via Commit.
+	Put(c Chunk)
+
+	// Version returns the storage-layer version protocol that this
+	// ChunkStore speaks.
+	Version() string
+
+	// Rebase brings this ChunkStore into sync with the persistent storage's
+	// current root.
+	Rebase()
+
+	// Root returns the currently-committed root hash of the database.
+	Root() hash.Hash
+
+	// Commit atomically attempts to move the root hash from last to
+	// current, flushing any Chunks Put since the last Commit.
+	Commit(current, last hash.Hash) bool
+
+	// Stats returns implementation-specific stats, or nil.
+	Stats() interface{}
+
+	// StatsSummary returns a human-readable summary of Stats.
+	StatsSummary() string
+
+	Close() error
+}
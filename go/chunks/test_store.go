@@ -0,0 +1,95 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"sync"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// TestStore is a simple in-memory ChunkStore used by tests.
+type TestStore struct {
+	mu     sync.Mutex
+	data   map[hash.Hash]Chunk
+	root   hash.Hash
+	Writes int
+}
+
+// NewTestStore returns a new, empty TestStore.
+func NewTestStore() *TestStore {
+	return &TestStore{data: map[hash.Hash]Chunk{}}
+}
+
+func (ts *TestStore) Get(h hash.Hash) Chunk {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.data[h]
+}
+
+func (ts *TestStore) GetMany(hashes hash.HashSet, foundChunks chan *Chunk) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	for h := range hashes {
+		if c, ok := ts.data[h]; ok {
+			c := c
+			foundChunks <- &c
+		}
+	}
+}
+
+func (ts *TestStore) Has(h hash.Hash) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	_, ok := ts.data[h]
+	return ok
+}
+
+func (ts *TestStore) HasMany(hashes hash.HashSet) (absent hash.HashSet) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	absent = hash.HashSet{}
+	for h := range hashes {
+		if _, ok := ts.data[h]; !ok {
+			absent.Insert(h)
+		}
+	}
+	return
+}
+
+func (ts *TestStore) Put(c Chunk) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.data[c.Hash()] = c
+	ts.Writes++
+}
+
+func (ts *TestStore) Version() string {
+	return "7.18"
+}
+
+func (ts *TestStore) Rebase() {}
+
+func (ts *TestStore) Root() hash.Hash {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.root
+}
+
+func (ts *TestStore) Commit(current, last hash.Hash) bool {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.root != last {
+		return false
+	}
+	ts.root = current
+	return true
+}
+
+func (ts *TestStore) Stats() interface{} { return nil }
+
+func (ts *TestStore) StatsSummary() string { return "TestStore" }
+
+func (ts *TestStore) Close() error { return nil }
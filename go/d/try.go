@@ -0,0 +1,30 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package d holds a handful of assertion helpers used throughout noms in
+// place of ad-hoc "if cond { panic(...) }" blocks.
+package d
+
+import "fmt"
+
+// PanicIfTrue panics with args if cond is true.
+func PanicIfTrue(cond bool, args ...interface{}) {
+	if cond {
+		panic(fmt.Sprint(args...))
+	}
+}
+
+// PanicIfFalse panics with args if cond is false.
+func PanicIfFalse(cond bool, args ...interface{}) {
+	if !cond {
+		panic(fmt.Sprint(args...))
+	}
+}
+
+// Chk panics with args if err is non-nil.
+func Chk(err error, args ...interface{}) {
+	if err != nil {
+		panic(fmt.Sprint(append(args, ": ", err)))
+	}
+}
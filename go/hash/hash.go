@@ -0,0 +1,72 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package hash defines a fixed-size hash type used to reference chunks and
+// Values throughout noms.
+package hash
+
+import (
+	"crypto/sha512"
+	"encoding/base32"
+)
+
+// ByteLen is the number of bytes used to store a Hash.
+const ByteLen = 20
+
+// Hash is the first 20 bytes of a sha-512/256 digest, used to uniquely
+// identify a chunk or Value.
+type Hash [ByteLen]byte
+
+// Of computes the Hash of data.
+func Of(data []byte) Hash {
+	r := sha512.Sum512_256(data)
+	h := Hash{}
+	copy(h[:], r[:ByteLen])
+	return h
+}
+
+// IsEmpty returns true if h is the zero Hash.
+func (h Hash) IsEmpty() bool {
+	return h == Hash{}
+}
+
+// String renders h as a base32-encoded string.
+func (h Hash) String() string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(h[:])
+}
+
+// HashSet is a set of Hashes.
+type HashSet map[Hash]struct{}
+
+// NewHashSet creates a HashSet containing hs.
+func NewHashSet(hs ...Hash) HashSet {
+	out := make(HashSet, len(hs))
+	for _, h := range hs {
+		out.Insert(h)
+	}
+	return out
+}
+
+// Insert adds h to hs.
+func (hs HashSet) Insert(h Hash) {
+	hs[h] = struct{}{}
+}
+
+// Has returns true if h is in hs.
+func (hs HashSet) Has(h Hash) bool {
+	_, ok := hs[h]
+	return ok
+}
+
+// Remove removes h from hs.
+func (hs HashSet) Remove(h Hash) {
+	delete(hs, h)
+}
+
+// HashSlice is a slice of Hashes, used where ordering matters.
+type HashSlice []Hash
+
+func (hs HashSlice) Len() int           { return len(hs) }
+func (hs HashSlice) Less(i, j int) bool { return hs[i].String() < hs[j].String() }
+func (hs HashSlice) Swap(i, j int)      { hs[i], hs[j] = hs[j], hs[i] }
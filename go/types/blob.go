@@ -0,0 +1,40 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import "github.com/attic-labs/noms/go/hash"
+
+// Blob is an ordered sequence of bytes.
+type Blob struct {
+	data []byte
+}
+
+// NewEmptyBlob returns a Blob with no data.
+func NewEmptyBlob() Blob {
+	return Blob{}
+}
+
+func (b Blob) Len() int {
+	return len(b.data)
+}
+
+func (b Blob) Equals(other Value) bool {
+	o, ok := other.(Blob)
+	if !ok || len(b.data) != len(o.data) {
+		return false
+	}
+	for i, c := range b.data {
+		if c != o.data[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b Blob) Hash() hash.Hash {
+	return hash.Of(append([]byte("blob:"), b.data...))
+}
+
+func (b Blob) WalkRefs(cb func(r Ref)) {}
@@ -0,0 +1,178 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/golang/snappy"
+	"github.com/ulikunitz/xz"
+)
+
+// ChunkCodec compresses and decompresses the bytes of a single chunk
+// between ValueStore and the underlying ChunkStore. Each registered codec
+// is identified by a one-byte magic prefix written ahead of its output, so
+// a single ChunkStore can hold chunks written under different codecs --
+// e.g. after switching the default, or after deliberately re-compressing
+// cold data with a higher-ratio codec.
+type ChunkCodec interface {
+	// Encode compresses data.
+	Encode(data []byte) []byte
+	// Decode decompresses data previously produced by Encode.
+	Decode(data []byte) ([]byte, error)
+	// Name identifies the codec, for diagnostics and CLI flags.
+	Name() string
+}
+
+// Magic prefix bytes. identityCodecMagic is 0 so that, should it ever be
+// needed, raw uncompressed chunks can be told apart from a corrupt prefix.
+const (
+	identityCodecMagic byte = iota
+	snappyCodecMagic
+	xzCodecMagic
+)
+
+var (
+	codecRegistryMu sync.RWMutex
+
+	codecsByMagic = map[byte]ChunkCodec{
+		identityCodecMagic: IdentityCodec,
+		snappyCodecMagic:   SnappyCodec,
+		xzCodecMagic:       XZCodec,
+	}
+
+	codecMagics = map[ChunkCodec]byte{
+		IdentityCodec: identityCodecMagic,
+		SnappyCodec:   snappyCodecMagic,
+		XZCodec:       xzCodecMagic,
+	}
+
+	// nextCustomMagic is the next magic prefix byte RegisterChunkCodec will
+	// hand out. 0 means exhausted (a byte only has 256 values, and wrapping
+	// back to 0 would collide with identityCodecMagic).
+	nextCustomMagic byte = xzCodecMagic + 1
+)
+
+// RegisterChunkCodec makes codec usable with NewValueStoreWithCodec and
+// readable by decodeChunk, by assigning it a fresh magic prefix byte.
+// Registering the same ChunkCodec value more than once is a no-op. Callers
+// should register custom codecs once at init time, before any ValueStore
+// using them reads or writes a chunk -- encodeChunk and decodeChunk look
+// the registry up on every call, so registering later is technically safe,
+// but a chunk written before registration can't be read back until it
+// happens.
+func RegisterChunkCodec(codec ChunkCodec) {
+	codecRegistryMu.Lock()
+	defer codecRegistryMu.Unlock()
+	if _, ok := codecMagics[codec]; ok {
+		return
+	}
+	d.PanicIfFalse(nextCustomMagic != 0, "RegisterChunkCodec: no magic prefix bytes left to assign")
+	magic := nextCustomMagic
+	nextCustomMagic++
+	codecsByMagic[magic] = codec
+	codecMagics[codec] = magic
+}
+
+type identityCodec struct{}
+
+func (identityCodec) Encode(data []byte) []byte         { return data }
+func (identityCodec) Decode(data []byte) ([]byte, error) { return data, nil }
+func (identityCodec) Name() string                      { return "none" }
+
+// IdentityCodec writes chunks uncompressed. Useful for tests and for data
+// that's already compressed (e.g. Blobs of media files).
+var IdentityCodec ChunkCodec = identityCodec{}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Encode(data []byte) []byte {
+	return snappy.Encode(nil, data)
+}
+
+func (snappyCodec) Decode(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+func (snappyCodec) Name() string { return "snappy" }
+
+// SnappyCodec is the default codec: fast to encode and decode, at a modest
+// compression ratio. Good for hot data written and read in the common case.
+var SnappyCodec ChunkCodec = snappyCodec{}
+
+type xzCodec struct{}
+
+func (xzCodec) Encode(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	w, err := xz.NewWriter(buf)
+	d.Chk(err, "creating xz writer")
+	_, err = w.Write(data)
+	d.Chk(err, "xz-compressing chunk")
+	d.Chk(w.Close(), "closing xz writer")
+	return buf.Bytes()
+}
+
+func (xzCodec) Decode(data []byte) ([]byte, error) {
+	r, err := xz.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (xzCodec) Name() string { return "xz" }
+
+// XZCodec trades encode/decode speed for a much higher compression ratio
+// than SnappyCodec. Good for cold data that's written once and read rarely,
+// where storage cost dominates.
+var XZCodec ChunkCodec = xzCodec{}
+
+// encodeChunk serializes and compresses v under codec, returning the chunk
+// that should be Put into the ChunkStore for h.
+func encodeChunk(h hash.Hash, v Value, codec ChunkCodec) chunks.Chunk {
+	codecRegistryMu.RLock()
+	magic, ok := codecMagics[codec]
+	codecRegistryMu.RUnlock()
+	d.PanicIfFalse(ok, "unregistered ChunkCodec: ", codec.Name(), " -- call types.RegisterChunkCodec first")
+	compressed := codec.Encode(EncodeValue(v))
+	data := make([]byte, 1+len(compressed))
+	data[0] = magic
+	copy(data[1:], compressed)
+	return chunks.NewChunkWithHash(h, data)
+}
+
+// decodeChunk reverses encodeChunk, panicking if c was written with a codec
+// this process doesn't know about.
+func decodeChunk(c chunks.Chunk) Value {
+	data := c.Data()
+	d.PanicIfFalse(len(data) >= 1, "empty chunk for ", c.Hash().String())
+	codecRegistryMu.RLock()
+	codec, ok := codecsByMagic[data[0]]
+	codecRegistryMu.RUnlock()
+	d.PanicIfFalse(ok, "chunk ", c.Hash().String(), " uses unknown codec prefix ", data[0])
+	raw, err := codec.Decode(data[1:])
+	d.Chk(err, "decompressing chunk ", c.Hash().String())
+	return DecodeValue(raw)
+}
+
+// DecodeChunk reverses encodeChunk like decodeChunk, but reports problems by
+// returning an error instead of panicking: unlike ReadValue, for which a
+// chunk that doesn't decode is an invariant violation, callers verifying
+// the integrity of a ChunkStore (go/check) need to treat exactly that as
+// reportable corruption, not a crash.
+func DecodeChunk(c chunks.Chunk) (v Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			v, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	return decodeChunk(c), nil
+}
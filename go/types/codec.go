@@ -0,0 +1,141 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// Tag bytes identifying the kind of Value that follows in the wire format
+// written by EncodeValue.
+const (
+	boolTag byte = iota
+	numberTag
+	stringTag
+	blobTag
+	listTag
+	structTag
+	refTag
+)
+
+// EncodeValue serializes v into noms's chunk wire format.
+func EncodeValue(v Value) []byte {
+	buf := &bytes.Buffer{}
+	encodeInto(buf, v)
+	return buf.Bytes()
+}
+
+func encodeInto(buf *bytes.Buffer, v Value) {
+	switch t := v.(type) {
+	case Bool:
+		buf.WriteByte(boolTag)
+		if t {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case Number:
+		buf.WriteByte(numberTag)
+		binary.Write(buf, binary.BigEndian, float64(t))
+	case String:
+		buf.WriteByte(stringTag)
+		writeBytes(buf, []byte(t))
+	case Blob:
+		buf.WriteByte(blobTag)
+		writeBytes(buf, t.data)
+	case List:
+		buf.WriteByte(listTag)
+		binary.Write(buf, binary.BigEndian, uint32(len(t.items)))
+		for _, item := range t.items {
+			encodeInto(buf, item)
+		}
+	case Struct:
+		buf.WriteByte(structTag)
+		writeBytes(buf, []byte(t.name))
+		names := t.sortedFieldNames()
+		binary.Write(buf, binary.BigEndian, uint32(len(names)))
+		for _, name := range names {
+			writeBytes(buf, []byte(name))
+			encodeInto(buf, t.data[name])
+		}
+	case Ref:
+		buf.WriteByte(refTag)
+		buf.Write(t.targetHash[:])
+		binary.Write(buf, binary.BigEndian, t.height)
+		writeBytes(buf, []byte(t.targetType.String()))
+	default:
+		panic(fmt.Sprintf("types: don't know how to encode %T", v))
+	}
+}
+
+func writeBytes(buf *bytes.Buffer, b []byte) {
+	binary.Write(buf, binary.BigEndian, uint32(len(b)))
+	buf.Write(b)
+}
+
+func readBytes(r *bytes.Reader) []byte {
+	var n uint32
+	binary.Read(r, binary.BigEndian, &n)
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+// DecodeValue deserializes data previously produced by EncodeValue.
+func DecodeValue(data []byte) Value {
+	r := bytes.NewReader(data)
+	return decodeFrom(r)
+}
+
+func decodeFrom(r *bytes.Reader) Value {
+	tag, err := r.ReadByte()
+	if err != nil {
+		panic(fmt.Sprintf("types: truncated value: %v", err))
+	}
+	switch tag {
+	case boolTag:
+		b, _ := r.ReadByte()
+		return Bool(b == 1)
+	case numberTag:
+		var f float64
+		binary.Read(r, binary.BigEndian, &f)
+		return Number(f)
+	case stringTag:
+		return String(readBytes(r))
+	case blobTag:
+		return Blob{readBytes(r)}
+	case listTag:
+		var n uint32
+		binary.Read(r, binary.BigEndian, &n)
+		items := make([]Value, n)
+		for i := range items {
+			items[i] = decodeFrom(r)
+		}
+		return List{items}
+	case structTag:
+		name := string(readBytes(r))
+		var n uint32
+		binary.Read(r, binary.BigEndian, &n)
+		data := StructData{}
+		for i := uint32(0); i < n; i++ {
+			fname := string(readBytes(r))
+			data[fname] = decodeFrom(r)
+		}
+		return Struct{name, data}
+	case refTag:
+		var h hash.Hash
+		r.Read(h[:])
+		var height uint64
+		binary.Read(r, binary.BigEndian, &height)
+		typeName := string(readBytes(r))
+		return Ref{h, height, &Type{typeName}}
+	default:
+		panic(fmt.Sprintf("types: unknown value tag %d", tag))
+	}
+}
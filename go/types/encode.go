@@ -0,0 +1,13 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import "fmt"
+
+// EncodedValue returns a human-readable rendering of v, suitable for
+// inclusion in diagnostic and assertion-failure messages.
+func EncodedValue(v Value) string {
+	return fmt.Sprintf("%#v", v)
+}
@@ -0,0 +1,62 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// List is an ordered sequence of Values.
+type List struct {
+	items []Value
+}
+
+// NewList returns a new List containing items, in order.
+func NewList(items ...Value) List {
+	return List{items}
+}
+
+func (l List) Len() int {
+	return len(l.items)
+}
+
+func (l List) Get(i int) Value {
+	return l.items[i]
+}
+
+func (l List) Equals(other Value) bool {
+	o, ok := other.(List)
+	if !ok || len(l.items) != len(o.items) {
+		return false
+	}
+	for i, v := range l.items {
+		if !v.Equals(o.items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l List) Hash() hash.Hash {
+	s := "list:"
+	for _, v := range l.items {
+		s += v.Hash().String()
+	}
+	return hash.Of([]byte(s))
+}
+
+func (l List) WalkRefs(cb func(r Ref)) {
+	for _, v := range l.items {
+		if r, ok := v.(Ref); ok {
+			cb(r)
+		}
+	}
+}
+
+func (l List) String() string {
+	return fmt.Sprintf("List(%d items)", len(l.items))
+}
@@ -0,0 +1,53 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// Bool is a noms boolean Value.
+type Bool bool
+
+func (b Bool) Equals(other Value) bool {
+	o, ok := other.(Bool)
+	return ok && b == o
+}
+
+func (b Bool) Hash() hash.Hash {
+	return hash.Of([]byte(fmt.Sprintf("bool:%t", b)))
+}
+
+func (b Bool) WalkRefs(cb func(r Ref)) {}
+
+// Number is a noms numeric Value, stored as a float64.
+type Number float64
+
+func (n Number) Equals(other Value) bool {
+	o, ok := other.(Number)
+	return ok && n == o
+}
+
+func (n Number) Hash() hash.Hash {
+	return hash.Of([]byte(fmt.Sprintf("number:%g", n)))
+}
+
+func (n Number) WalkRefs(cb func(r Ref)) {}
+
+// String is a noms string Value.
+type String string
+
+func (s String) Equals(other Value) bool {
+	o, ok := other.(String)
+	return ok && s == o
+}
+
+func (s String) Hash() hash.Hash {
+	return hash.Of([]byte("string:" + string(s)))
+}
+
+func (s String) WalkRefs(cb func(r Ref)) {}
@@ -0,0 +1,86 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// sizeCache is a byte-budgeted LRU cache keyed by hash.Hash, used to give
+// ValueStore two independently sized caching tiers: one of raw
+// chunks.Chunk bytes straight from the ChunkStore, and one of decoded
+// Values. It's deliberately untyped (storing interface{}) so both tiers can
+// share the same eviction bookkeeping; see ValueStore's rawCache and decodedCache.
+type sizeCache struct {
+	mu      sync.Mutex
+	maxSize uint64
+	size    uint64
+	ll      *list.List
+	items   map[hash.Hash]*list.Element
+}
+
+type sizeCacheEntry struct {
+	h      hash.Hash
+	value  interface{}
+	weight uint64
+}
+
+func newSizeCache(maxSize uint64) *sizeCache {
+	return &sizeCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   map[hash.Hash]*list.Element{},
+	}
+}
+
+func (c *sizeCache) get(h hash.Hash) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[h]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*sizeCacheEntry).value, true
+}
+
+// add inserts value under h, weighted at weight bytes, evicting
+// least-recently-used entries as needed to stay under maxSize. A weight
+// larger than maxSize is still stored -- this cache bounds steady-state
+// memory use, it doesn't reject individual large values.
+func (c *sizeCache) add(h hash.Hash, value interface{}, weight uint64) {
+	if c.maxSize == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[h]; ok {
+		c.ll.MoveToFront(e)
+		old := e.Value.(*sizeCacheEntry)
+		c.size += weight - old.weight
+		e.Value = &sizeCacheEntry{h, value, weight}
+	} else {
+		e := c.ll.PushFront(&sizeCacheEntry{h, value, weight})
+		c.items[h] = e
+		c.size += weight
+	}
+	for c.size > c.maxSize && c.ll.Len() > 1 {
+		c.removeOldest()
+	}
+}
+
+func (c *sizeCache) removeOldest() {
+	e := c.ll.Back()
+	if e == nil {
+		return
+	}
+	c.ll.Remove(e)
+	entry := e.Value.(*sizeCacheEntry)
+	delete(c.items, entry.h)
+	c.size -= entry.weight
+}
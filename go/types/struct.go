@@ -0,0 +1,74 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"sort"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// StructData is the set of field values that make up a Struct, keyed by
+// field name.
+type StructData map[string]Value
+
+// Struct is a Value with a fixed set of named fields.
+type Struct struct {
+	name string
+	data StructData
+}
+
+// NewStruct returns a new Struct called name with the given fields.
+func NewStruct(name string, data StructData) Struct {
+	return Struct{name, data}
+}
+
+func (s Struct) Name() string {
+	return s.name
+}
+
+func (s Struct) Field(name string) (Value, bool) {
+	v, ok := s.data[name]
+	return v, ok
+}
+
+func (s Struct) Equals(other Value) bool {
+	o, ok := other.(Struct)
+	if !ok || s.name != o.name || len(s.data) != len(o.data) {
+		return false
+	}
+	for k, v := range s.data {
+		ov, ok := o.data[k]
+		if !ok || !v.Equals(ov) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Struct) sortedFieldNames() []string {
+	names := make([]string, 0, len(s.data))
+	for k := range s.data {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s Struct) Hash() hash.Hash {
+	str := "struct:" + s.name
+	for _, k := range s.sortedFieldNames() {
+		str += k + ":" + s.data[k].Hash().String()
+	}
+	return hash.Of([]byte(str))
+}
+
+func (s Struct) WalkRefs(cb func(r Ref)) {
+	for _, k := range s.sortedFieldNames() {
+		if r, ok := s.data[k].(Ref); ok {
+			cb(r)
+		}
+	}
+}
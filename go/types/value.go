@@ -0,0 +1,23 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import "github.com/attic-labs/noms/go/hash"
+
+// Value is the interface that all noms values implement.
+type Value interface {
+	Equals(other Value) bool
+	Hash() hash.Hash
+
+	// WalkRefs calls cb on each Ref directly contained within this Value,
+	// but does not recurse.
+	WalkRefs(cb func(r Ref))
+}
+
+// ValueSlice is a slice of Values, used for building collections in tests
+// and small programs.
+type ValueSlice []Value
+
+func (vs ValueSlice) Len() int { return len(vs) }
@@ -0,0 +1,313 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"sync"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// nomsVersion is the storage-layer protocol version that ValueStore expects
+// the underlying chunks.ChunkStore to speak. A ChunkStore reporting any
+// other version is refused.
+const nomsVersion = "7.18"
+
+// defaultBufferedChunksMax is the default number of bytes of not-yet-flushed
+// chunk data a ValueStore will hold before proactively flushing.
+const defaultBufferedChunksMax = 64 * 1024 * 1024
+
+// ValueStore provides methods to read and write noms Values to a
+// chunks.ChunkStore. It minds the read/write cycle: WriteValue stashes
+// written Values in memory until Flush (or an internal high-water mark) asks
+// the ChunkStore to durably Put them, in an order that respects the
+// reference graph -- a chunk's children must be written before it.
+type ValueStore struct {
+	cs chunks.ChunkStore
+
+	mu                 sync.Mutex
+	bufferedChunks     map[hash.Hash]Value
+	bufferedOrder      hash.HashSlice
+	bufferedChunkSize  uint64
+	bufferedChunksMax  uint64
+	withBufferedChunks map[hash.Hash]hash.HashSlice
+	relationshipNodes  hash.HashSet
+
+	pendingPuts  map[hash.Hash]Value
+	rawCache     *sizeCache // hash.Hash -> chunks.Chunk, sized in bytes
+	decodedCache *sizeCache // hash.Hash -> Value, sized in estimated bytes
+	codec        ChunkCodec
+
+	cond  *sync.Cond
+	async *asyncPersister // non-nil iff this ValueStore was made with NewValueStoreAsync
+}
+
+// defaultRawCacheSize and defaultDecodedCacheSize are the cache budgets
+// used by NewValueStore. The raw tier is larger because it's cheap (no
+// decode cost to keep around) and is the thing that saves a ChunkStore
+// round-trip; the decoded tier saves CPU on top of that for values that
+// get read repeatedly (e.g. diff, walk).
+const (
+	defaultRawCacheSize     = 64 << 20
+	defaultDecodedCacheSize = 16 << 20
+)
+
+// NewValueStore returns a ValueStore backed by cs, using sensible defaults
+// for cache and buffering sizes.
+func NewValueStore(cs chunks.ChunkStore) *ValueStore {
+	return NewValueStoreWithCacheSizes(cs, defaultRawCacheSize, defaultDecodedCacheSize)
+}
+
+// NewValueStoreWithCacheSizes returns a ValueStore backed by cs with two
+// independently sized LRU caches sitting between it and the ChunkStore: one
+// of raw, still-encoded chunk bytes (budgeted rawBytes), and one of decoded
+// Values (budgeted decodedBytes). Tune these to trade memory for the I/O
+// cost of re-fetching and the CPU cost of re-decoding, respectively; either
+// may be 0 to disable that tier.
+func NewValueStoreWithCacheSizes(cs chunks.ChunkStore, rawBytes, decodedBytes uint64) *ValueStore {
+	return newValueStore(cs, rawBytes, decodedBytes, defaultBufferedChunksMax, SnappyCodec)
+}
+
+// NewValueStoreWithCodec returns a ValueStore backed by cs that compresses
+// every chunk it writes with codec (e.g. types.SnappyCodec, types.XZCodec,
+// or a custom ChunkCodec registered with RegisterChunkCodec), identifying
+// the codec used with a magic prefix byte on each chunk so a store may
+// freely mix chunks written under different codecs.
+func NewValueStoreWithCodec(cs chunks.ChunkStore, codec ChunkCodec) *ValueStore {
+	return newValueStore(cs, defaultRawCacheSize, defaultDecodedCacheSize, defaultBufferedChunksMax, codec)
+}
+
+// NewTestValueStore returns a ValueStore over a fresh, empty
+// chunks.TestStore. It exists for use by tests in this and other packages
+// that need a ValueStore but don't care about its backing store.
+func NewTestValueStore() *ValueStore {
+	return NewValueStore(chunks.NewTestStore())
+}
+
+// newValueStoreWithCacheAndPending is a convenience for tests that only
+// want to tune the cache and buffering sizes together; it applies cacheSize
+// to both cache tiers.
+func newValueStoreWithCacheAndPending(cs chunks.ChunkStore, cacheSize, bufferedChunksMax uint64) *ValueStore {
+	return newValueStore(cs, cacheSize, cacheSize, bufferedChunksMax, SnappyCodec)
+}
+
+func newValueStore(cs chunks.ChunkStore, rawBytes, decodedBytes, bufferedChunksMax uint64, codec ChunkCodec) *ValueStore {
+	lvs := &ValueStore{
+		cs:                 cs,
+		bufferedChunks:     map[hash.Hash]Value{},
+		withBufferedChunks: map[hash.Hash]hash.HashSlice{},
+		relationshipNodes:  hash.HashSet{},
+		pendingPuts:        map[hash.Hash]Value{},
+		rawCache:           newSizeCache(rawBytes),
+		decodedCache:       newSizeCache(decodedBytes),
+		bufferedChunksMax:  bufferedChunksMax,
+		codec:              codec,
+	}
+	lvs.cond = sync.NewCond(&lvs.mu)
+	return lvs
+}
+
+// ChunkStore returns the chunks.ChunkStore backing lvs.
+func (lvs *ValueStore) ChunkStore() chunks.ChunkStore {
+	return lvs.cs
+}
+
+func (lvs *ValueStore) checkVersion() {
+	d.PanicIfFalse(lvs.cs.Version() == nomsVersion, "unsupported ChunkStore version: ", lvs.cs.Version())
+}
+
+// ReadValue reads and decodes the Value identified by h, or returns nil if
+// it is not present. Values written but not yet Flushed are still visible.
+func (lvs *ValueStore) ReadValue(h hash.Hash) Value {
+	lvs.mu.Lock()
+	defer lvs.mu.Unlock()
+	return lvs.readValueLocked(h)
+}
+
+func (lvs *ValueStore) readValueLocked(h hash.Hash) Value {
+	if v, ok := lvs.pendingPuts[h]; ok {
+		return v
+	}
+	if v, ok := lvs.decodedCache.get(h); ok {
+		return v.(Value)
+	}
+	if raw, ok := lvs.rawCache.get(h); ok {
+		c := raw.(chunks.Chunk)
+		v := decodeChunk(c)
+		lvs.cacheDecoded(h, v)
+		return v
+	}
+	lvs.checkVersion()
+	c := lvs.cs.Get(h)
+	if c.IsEmpty() {
+		return nil
+	}
+	lvs.cacheRaw(c)
+	v := decodeChunk(c)
+	lvs.cacheDecoded(h, v)
+	return v
+}
+
+func (lvs *ValueStore) cacheRaw(c chunks.Chunk) {
+	lvs.rawCache.add(c.Hash(), c, uint64(len(c.Data())))
+}
+
+func (lvs *ValueStore) cacheDecoded(h hash.Hash, v Value) {
+	lvs.decodedCache.add(h, v, uint64(len(EncodeValue(v))))
+}
+
+// ReadManyValues reads and decodes the Values identified by hashes and
+// streams each one found onto foundValues as it becomes available. It does
+// not close foundValues.
+func (lvs *ValueStore) ReadManyValues(hashes hash.HashSet, foundValues chan<- Value) {
+	lvs.mu.Lock()
+	remaining := hash.HashSet{}
+	for h := range hashes {
+		if v, ok := lvs.pendingPuts[h]; ok {
+			foundValues <- v
+			continue
+		}
+		if v, ok := lvs.decodedCache.get(h); ok {
+			foundValues <- v.(Value)
+			continue
+		}
+		if raw, ok := lvs.rawCache.get(h); ok {
+			c := raw.(chunks.Chunk)
+			v := decodeChunk(c)
+			lvs.cacheDecoded(h, v)
+			foundValues <- v
+			continue
+		}
+		remaining.Insert(h)
+	}
+	lvs.mu.Unlock()
+	if len(remaining) == 0 {
+		return
+	}
+
+	lvs.checkVersion()
+	foundChunks := make(chan *chunks.Chunk, len(remaining))
+	lvs.cs.GetMany(remaining, foundChunks)
+	close(foundChunks)
+	for c := range foundChunks {
+		v := decodeChunk(*c)
+		lvs.mu.Lock()
+		lvs.cacheRaw(*c)
+		lvs.cacheDecoded(c.Hash(), v)
+		lvs.mu.Unlock()
+		foundValues <- v
+	}
+}
+
+// WriteValue stashes v in memory, to be durably Put the next time Flush is
+// called (possibly triggered from within this call, if the amount of
+// buffered data has grown past bufferedChunksMax). It returns a Ref to v.
+func (lvs *ValueStore) WriteValue(v Value) Ref {
+	d.PanicIfTrue(v == nil, "cannot write nil Value")
+	lvs.mu.Lock()
+	defer lvs.mu.Unlock()
+
+	r := NewRef(v)
+	h := r.TargetHash()
+	if _, ok := lvs.bufferedChunks[h]; ok {
+		return r
+	}
+
+	lvs.bufferedChunks[h] = v
+	lvs.bufferedOrder = append(lvs.bufferedOrder, h)
+	lvs.bufferedChunkSize += uint64(len(EncodeValue(v)))
+	lvs.pendingPuts[h] = v
+
+	var children hash.HashSlice
+	v.WalkRefs(func(cr Ref) {
+		ch := cr.TargetHash()
+		if _, ok := lvs.bufferedChunks[ch]; ok {
+			children = append(children, ch)
+		}
+	})
+	if len(children) > 0 {
+		lvs.withBufferedChunks[h] = children
+		lvs.relationshipNodes.Insert(h)
+		for _, ch := range children {
+			lvs.relationshipNodes.Insert(ch)
+		}
+	}
+
+	if lvs.async != nil {
+		lvs.cond.Broadcast() // wake the persister: there's new work
+		for lvs.bufferedChunkSize >= lvs.async.opts.HighWater {
+			lvs.cond.Wait() // backpressure: wait for the persister to make room
+		}
+	} else if lvs.bufferedChunkSize > lvs.bufferedChunksMax {
+		lvs.flushLocked()
+	}
+	return r
+}
+
+// Flush Puts every buffered chunk into the ChunkStore, children before
+// parents, and blocks until that's done. On a ValueStore created with
+// NewValueStoreAsync, this is a drain-and-wait barrier: it blocks until the
+// background persister has caught up, rather than doing the work itself.
+func (lvs *ValueStore) Flush() {
+	lvs.mu.Lock()
+	defer lvs.mu.Unlock()
+	if lvs.async != nil {
+		lvs.cond.Broadcast()
+		for lvs.bufferedChunkSize > 0 {
+			lvs.cond.Wait()
+		}
+		return
+	}
+	lvs.flushLocked()
+}
+
+func (lvs *ValueStore) flushLocked() {
+	lvs.checkVersion()
+	flushed := hash.HashSet{}
+	var flushOne func(h hash.Hash)
+	flushOne = func(h hash.Hash) {
+		if flushed.Has(h) {
+			return
+		}
+		if children, ok := lvs.withBufferedChunks[h]; ok {
+			for _, ch := range children {
+				flushOne(ch)
+			}
+		}
+		if v, ok := lvs.bufferedChunks[h]; ok {
+			c := encodeChunk(h, v, lvs.codec)
+			lvs.cs.Put(c)
+			lvs.cacheRaw(c)
+			flushed.Insert(h)
+		}
+	}
+
+	// Phase 1: chunks that participate in a known parent/child relationship,
+	// in write order -- this flushes children before the parents that were
+	// recorded as depending on them.
+	for _, h := range lvs.bufferedOrder {
+		if lvs.relationshipNodes.Has(h) {
+			flushOne(h)
+		}
+	}
+	// Phase 2: whatever's left has no known relationship to anything else
+	// still buffered (e.g. it was written against a Value whose children
+	// weren't buffered at the time), so there's nothing to order it
+	// against. Flush it last, in write order.
+	for _, h := range lvs.bufferedOrder {
+		flushOne(h)
+	}
+
+	lvs.cs.Commit(lvs.cs.Root(), lvs.cs.Root())
+
+	lvs.bufferedChunks = map[hash.Hash]Value{}
+	lvs.bufferedOrder = nil
+	lvs.bufferedChunkSize = 0
+	lvs.withBufferedChunks = map[hash.Hash]hash.HashSlice{}
+	lvs.relationshipNodes = hash.HashSet{}
+	lvs.pendingPuts = map[hash.Hash]Value{}
+}
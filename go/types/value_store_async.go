@@ -0,0 +1,193 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// AsyncOptions configures a ValueStore created by NewValueStoreAsync.
+type AsyncOptions struct {
+	// LowWater is the number of buffered-but-unpersisted bytes above which
+	// the background persister wakes up and starts draining.
+	LowWater uint64
+	// HighWater is the number of buffered-but-unpersisted bytes at which
+	// WriteValue starts applying backpressure, blocking the caller until
+	// the persister has made room.
+	HighWater uint64
+	// MaxInflight bounds how many chunks.ChunkStore.Put calls the
+	// persister will have outstanding at once. Defaults to 4.
+	MaxInflight int
+}
+
+func (o AsyncOptions) maxInflight() int {
+	if o.MaxInflight <= 0 {
+		return 4
+	}
+	return o.MaxInflight
+}
+
+// AsyncStats reports the background persister's current progress.
+type AsyncStats struct {
+	// QueueDepth is the number of chunks currently buffered and waiting to
+	// be persisted.
+	QueueDepth int
+	// BytesInFlight is the encoded size of every chunk written but not yet
+	// persisted: those counted in QueueDepth plus the batch, if any, that
+	// the persister is writing right now.
+	BytesInFlight uint64
+	// ChunksPersisted is the total number of chunks this ValueStore has
+	// Put to its ChunkStore since it was created.
+	ChunksPersisted uint64
+}
+
+// asyncPersister is the background goroutine that drains a ValueStore's
+// bufferedChunks, modeled on moss's persister: it wakes whenever there's
+// buffered work above LowWater, and proactively writes it out so that
+// WriteValue rarely has to block.
+type asyncPersister struct {
+	opts            AsyncOptions
+	stopping        bool
+	stopped         chan struct{}
+	chunksPersisted uint64 // atomic
+}
+
+// NewValueStoreAsync returns a ValueStore backed by cs whose Flush merely
+// waits for a background persister goroutine to catch up, rather than
+// doing the work of writing buffered chunks itself. WriteValue still
+// returns immediately unless the buffered backlog has grown past
+// opts.HighWater, at which point it blocks until the persister has made
+// room. Call Close to stop the persister and release cs.
+func NewValueStoreAsync(cs chunks.ChunkStore, opts AsyncOptions) *ValueStore {
+	lvs := newValueStore(cs, defaultRawCacheSize, defaultDecodedCacheSize, opts.HighWater, SnappyCodec)
+	lvs.async = &asyncPersister{opts: opts, stopped: make(chan struct{})}
+	go lvs.async.run(lvs)
+	return lvs
+}
+
+func (p *asyncPersister) run(lvs *ValueStore) {
+	defer close(p.stopped)
+	for {
+		lvs.mu.Lock()
+		for !p.stopping && lvs.bufferedChunkSize <= p.opts.LowWater {
+			lvs.cond.Wait()
+		}
+		if p.stopping && lvs.bufferedChunkSize == 0 {
+			lvs.mu.Unlock()
+			return
+		}
+
+		// Snapshot and clear the current backlog so that writes arriving
+		// while we're persisting this batch accumulate into a fresh one,
+		// rather than racing with the drain below. bufferedChunkSize itself is
+		// left alone here -- it's what Flush and WriteValue's backpressure
+		// wait on, and it must keep counting this batch's bytes as
+		// outstanding until persist has actually made them durable, not
+		// merely handed them off.
+		order := lvs.bufferedOrder
+		withChildren := lvs.withBufferedChunks
+		buffered := lvs.bufferedChunks
+		batchSize := lvs.bufferedChunkSize
+		lvs.bufferedChunks = map[hash.Hash]Value{}
+		lvs.bufferedOrder = nil
+		lvs.withBufferedChunks = map[hash.Hash]hash.HashSlice{}
+		lvs.relationshipNodes = hash.HashSet{}
+		lvs.mu.Unlock()
+
+		p.persist(lvs, order, withChildren, buffered)
+
+		lvs.mu.Lock()
+		lvs.bufferedChunkSize -= batchSize
+		lvs.cond.Broadcast() // wake Flush/WriteValue: this batch is durably committed
+		lvs.mu.Unlock()
+	}
+}
+
+// persist writes out one snapshotted batch, respecting the partial order
+// recorded in withChildren (a chunk is never Put before its recorded
+// children), while allowing independent branches to persist concurrently,
+// bounded by opts.MaxInflight, and only returns once the batch has been
+// committed -- mirroring the sync path's flushLocked, so a chunk a caller
+// is waiting on (via Flush) is actually durable once persist returns, not
+// merely handed to the ChunkStore's write buffer.
+func (p *asyncPersister) persist(lvs *ValueStore, order hash.HashSlice, withChildren map[hash.Hash]hash.HashSlice, buffered map[hash.Hash]Value) {
+	done := make(map[hash.Hash]chan struct{}, len(order))
+	for _, h := range order {
+		done[h] = make(chan struct{})
+	}
+	sem := make(chan struct{}, p.opts.maxInflight())
+	var wg sync.WaitGroup
+
+	var persistOne func(h hash.Hash)
+	persistOne = func(h hash.Hash) {
+		defer wg.Done()
+		defer close(done[h])
+		for _, ch := range withChildren[h] {
+			<-done[ch]
+		}
+		v, ok := buffered[h]
+		if !ok {
+			return
+		}
+		sem <- struct{}{}
+		c := encodeChunk(h, v, lvs.codec)
+		lvs.cs.Put(c)
+		lvs.mu.Lock()
+		lvs.cacheRaw(c)
+		lvs.mu.Unlock()
+		atomic.AddUint64(&p.chunksPersisted, 1)
+		<-sem
+	}
+
+	for _, h := range order {
+		wg.Add(1)
+		go persistOne(h)
+	}
+	wg.Wait()
+	lvs.cs.Commit(lvs.cs.Root(), lvs.cs.Root())
+
+	// Once a batch is durably committed, its Values are reachable via
+	// rawCache (and decodedCache, once re-read) through lvs.cs, so holding
+	// them in pendingPuts too would just pin every Value ever written for
+	// the ValueStore's lifetime -- unbounded memory growth that defeats the
+	// whole point of HighWater backpressure. The sync path clears
+	// pendingPuts the same way once flushLocked commits (value_store.go).
+	lvs.mu.Lock()
+	for _, h := range order {
+		delete(lvs.pendingPuts, h)
+	}
+	lvs.mu.Unlock()
+}
+
+// Stats reports the background persister's current progress. It panics if
+// lvs wasn't created with NewValueStoreAsync.
+func (lvs *ValueStore) Stats() AsyncStats {
+	d.PanicIfFalse(lvs.async != nil, "Stats is only valid on a ValueStore created with NewValueStoreAsync")
+	lvs.mu.Lock()
+	defer lvs.mu.Unlock()
+	return AsyncStats{
+		QueueDepth:      len(lvs.bufferedOrder),
+		BytesInFlight:   lvs.bufferedChunkSize,
+		ChunksPersisted: atomic.LoadUint64(&lvs.async.chunksPersisted),
+	}
+}
+
+// Close stops the background persister, blocking until it has drained any
+// remaining buffered chunks, then closes the underlying ChunkStore. It
+// panics if lvs wasn't created with NewValueStoreAsync.
+func (lvs *ValueStore) Close() error {
+	d.PanicIfFalse(lvs.async != nil, "Close is only valid on a ValueStore created with NewValueStoreAsync")
+	lvs.mu.Lock()
+	lvs.async.stopping = true
+	lvs.cond.Broadcast()
+	lvs.mu.Unlock()
+	<-lvs.async.stopped
+	return lvs.cs.Close()
+}
@@ -0,0 +1,142 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/testify/assert"
+)
+
+// recordingChunkStore records the order chunks actually land in the
+// underlying ChunkStore, tolerating the concurrent Puts an asyncPersister
+// issues -- unlike checkingChunkStore, it doesn't demand one true order,
+// just lets the test ask "did a land before b?".
+type recordingChunkStore struct {
+	chunks.ChunkStore
+	mu      sync.Mutex
+	order   hash.HashSlice
+	commits int
+}
+
+func (r *recordingChunkStore) Put(c chunks.Chunk) {
+	r.mu.Lock()
+	r.order = append(r.order, c.Hash())
+	r.mu.Unlock()
+	r.ChunkStore.Put(c)
+}
+
+func (r *recordingChunkStore) Commit(current, last hash.Hash) bool {
+	r.mu.Lock()
+	r.commits++
+	r.mu.Unlock()
+	return r.ChunkStore.Commit(current, last)
+}
+
+func (r *recordingChunkStore) indexOf(h hash.Hash) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, oh := range r.order {
+		if oh == h {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestAsyncFlushRespectsPartialOrder(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recordingChunkStore{ChunkStore: chunks.NewTestStore()}
+	vs := NewValueStoreAsync(rec, AsyncOptions{LowWater: 0, HighWater: 1 << 20, MaxInflight: 4})
+	defer vs.Close()
+
+	sr, nr := vs.WriteValue(String("oy")), vs.WriteValue(Number(42))
+	mlr := vs.WriteValue(NewList(sr, nr))
+	lr := vs.WriteValue(NewList(mlr))
+
+	vs.Flush()
+
+	before := func(a, b Ref) bool { return rec.indexOf(a.TargetHash()) < rec.indexOf(b.TargetHash()) }
+	assert.True(before(sr, mlr), "oy should persist before its parent list")
+	assert.True(before(nr, mlr), "42 should persist before its parent list")
+	assert.True(before(mlr, lr), "the inner list should persist before the outer one")
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.True(rec.commits > 0, "the async persister must Commit what it Puts, or a real (non-TestStore) ChunkStore would never see it durably")
+}
+
+func TestAsyncFlushBarrierWaitsForCommit(t *testing.T) {
+	assert := assert.New(t)
+	rec := &recordingChunkStore{ChunkStore: chunks.NewTestStore()}
+	vs := NewValueStoreAsync(rec, AsyncOptions{LowWater: 0, HighWater: 1 << 20, MaxInflight: 1})
+	defer vs.Close()
+
+	for i := 0; i < 20; i++ {
+		vs.WriteValue(String(string(rune('a' + i))))
+	}
+	vs.Flush()
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	assert.True(rec.commits > 0, "Flush must not return before the persister has committed the batch it was waiting on")
+	assert.Zero(vs.Stats().BytesInFlight, "Flush must not return while bytes are still only snapshotted, not yet persisted")
+}
+
+func TestAsyncFlushReleasesPendingPuts(t *testing.T) {
+	assert := assert.New(t)
+	vs := NewValueStoreAsync(chunks.NewTestStore(), AsyncOptions{LowWater: 0, HighWater: 1 << 20, MaxInflight: 4})
+	defer vs.Close()
+
+	for i := 0; i < 20; i++ {
+		vs.WriteValue(String(string(rune('a' + i))))
+	}
+	vs.Flush()
+
+	// Once a batch is durably committed, its Values are reachable via
+	// rawCache through cs, so pendingPuts must let them go -- otherwise
+	// every Value ever written stays strongly referenced for the
+	// ValueStore's lifetime, defeating HighWater's bounded-memory design.
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	assert.Empty(vs.pendingPuts, "Flush should release committed Values from pendingPuts")
+}
+
+func TestAsyncStatsAndClose(t *testing.T) {
+	assert := assert.New(t)
+	vs := NewValueStoreAsync(chunks.NewTestStore(), AsyncOptions{LowWater: 0, HighWater: 1 << 20, MaxInflight: 2})
+
+	vs.WriteValue(String("a"))
+	vs.WriteValue(String("b"))
+	vs.Flush()
+
+	stats := vs.Stats()
+	assert.Zero(stats.QueueDepth)
+	assert.Zero(stats.BytesInFlight)
+	assert.Equal(uint64(2), stats.ChunksPersisted)
+
+	assert.NoError(vs.Close())
+}
+
+func TestAsyncWriteValueBackpressure(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := NewValueStoreAsync(cs, AsyncOptions{LowWater: 0, HighWater: 8, MaxInflight: 1})
+	defer vs.Close()
+
+	// Each of these Strings encodes to 6 bytes, so any write made while an
+	// earlier one is still unpersisted brings bufferedChunkSize to HighWater
+	// and WriteValue blocks until the persister catches up. The loop and the
+	// Flush below must still complete, leaving nothing in flight.
+	for i := 0; i < 50; i++ {
+		vs.WriteValue(String(string(rune('a' + i%26))))
+	}
+	vs.Flush()
+
+	assert.Zero(vs.Stats().BytesInFlight)
+}
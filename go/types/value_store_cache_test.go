@@ -0,0 +1,68 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/testify/assert"
+)
+
+// countingChunkStore counts Gets, so tests can assert the cache spared it a
+// round-trip.
+type countingChunkStore struct {
+	chunks.ChunkStore
+	gets int
+}
+
+func (ccs *countingChunkStore) Get(h hash.Hash) chunks.Chunk {
+	ccs.gets++
+	return ccs.ChunkStore.Get(h)
+}
+
+func TestValueStoreDecodedCacheAvoidsChunkStoreGet(t *testing.T) {
+	assert := assert.New(t)
+	ccs := &countingChunkStore{ChunkStore: chunks.NewTestStore()}
+	vs := NewValueStoreWithCacheSizes(ccs, 0, 1<<20)
+
+	h := vs.WriteValue(String("hello")).TargetHash()
+	vs.Flush()
+
+	assert.NotNil(vs.ReadValue(h))
+	gets := ccs.gets
+	assert.NotNil(vs.ReadValue(h))
+	assert.Equal(gets, ccs.gets, "decoded cache hit should not call ChunkStore.Get again")
+}
+
+func TestValueStoreRawCacheAvoidsChunkStoreGet(t *testing.T) {
+	assert := assert.New(t)
+	ccs := &countingChunkStore{ChunkStore: chunks.NewTestStore()}
+	// Disable the decoded tier so only the raw tier can satisfy the second read.
+	vs := NewValueStoreWithCacheSizes(ccs, 1<<20, 0)
+
+	h := vs.WriteValue(String("hello")).TargetHash()
+	vs.Flush()
+
+	assert.NotNil(vs.ReadValue(h))
+	gets := ccs.gets
+	assert.NotNil(vs.ReadValue(h))
+	assert.Equal(gets, ccs.gets, "raw cache hit should not call ChunkStore.Get again")
+}
+
+func TestValueStoreNoCacheHitsChunkStoreEveryTime(t *testing.T) {
+	assert := assert.New(t)
+	ccs := &countingChunkStore{ChunkStore: chunks.NewTestStore()}
+	vs := NewValueStoreWithCacheSizes(ccs, 0, 0)
+
+	h := vs.WriteValue(String("hello")).TargetHash()
+	vs.Flush()
+
+	vs.ReadValue(h)
+	gets := ccs.gets
+	vs.ReadValue(h)
+	assert.Equal(gets+1, ccs.gets, "with both caches disabled every read should hit the ChunkStore")
+}
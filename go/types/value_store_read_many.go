@@ -0,0 +1,227 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"sync"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// ReadOptions tunes ReadManyValuesWithOptions.
+type ReadOptions struct {
+	// Concurrency is the number of parallel chunks.ChunkStore.Get calls to
+	// have outstanding at once. Defaults to 1 (fully sequential).
+	Concurrency int
+	// PrefetchDepth is how many levels of Ref children, discovered while
+	// decoding a requested Value, to proactively fetch and cache ahead of
+	// being asked for them. 0 disables prefetching.
+	PrefetchDepth int
+}
+
+type readJob struct {
+	h      hash.Hash
+	depth  int
+	wanted bool // false for a job that only exists to warm the cache via prefetch
+}
+
+// readJobState is the per-hash record ReadManyValuesWithOptions uses to
+// dedupe jobs while still honoring every wanted=true request for that hash,
+// even one that arrives after the (possibly unwanted) job for it is already
+// running or done. wanted starts as whatever the first request asked for and
+// can only be upgraded false->true; v and done are set once by the job that
+// actually ran.
+type readJobState struct {
+	wanted bool
+	done   bool
+	v      Value
+}
+
+// readJobQueue is an unbounded FIFO of readJobs. It exists because jobs are
+// pushed onto it both by the caller (seeding the initial hashes) and by
+// workers themselves (feeding prefetch discoveries back into the
+// pipeline), which are also its only consumers: a plain channel would
+// either need a blocking send (deadlocking a worker that tries to hand a
+// job to a fully-subscribed peer doing the same thing) or a fixed buffer
+// size (an arbitrary cap on prefetch fan-out). push never blocks and never
+// spawns a goroutine, so fan-out is bounded only by memory, not by
+// goroutine count.
+type readJobQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []readJob
+	closed bool
+}
+
+func newReadJobQueue() *readJobQueue {
+	q := &readJobQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *readJobQueue) push(j readJob) {
+	q.mu.Lock()
+	q.items = append(q.items, j)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// close marks the queue as done accepting new work; pop returns ok=false
+// once it has drained everything pushed before close was called.
+func (q *readJobQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+func (q *readJobQueue) pop() (readJob, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return readJob{}, false
+	}
+	j := q.items[0]
+	q.items = q.items[1:]
+	return j, true
+}
+
+// ReadManyValuesWithOptions is ReadManyValues with control over I/O
+// concurrency against the ChunkStore and the ability to prefetch Ref
+// targets discovered while decoding, the way a git object walker would.
+// Like ReadManyValues, it streams each requested-and-found Value onto out
+// (in no particular order) and does not close out; Values pulled in purely
+// for prefetch are cached but not sent.
+func (lvs *ValueStore) ReadManyValuesWithOptions(hashes hash.HashSet, opts ReadOptions, out chan<- Value) {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := newReadJobQueue()
+	var wg sync.WaitGroup
+
+	// states tracks, per hash, whether any caller has asked for it with
+	// wanted=true and (once the job has run) the decoded Value -- so that a
+	// wanted request arriving after an unwanted (prefetch-only) request for
+	// the same hash is already in flight still gets delivered, instead of
+	// being silently dropped as "already inflight". Workers start consuming
+	// before the seed loop below finishes enqueueing, so a worker's prefetch
+	// can race ahead of the seed loop reaching that same hash.
+	var statesMu sync.Mutex
+	states := map[hash.Hash]*readJobState{}
+	enqueue := func(h hash.Hash, depth int, wanted bool) {
+		statesMu.Lock()
+		if st, ok := states[h]; ok {
+			upgraded := wanted && !st.wanted
+			if upgraded {
+				st.wanted = true
+			}
+			done, v := st.done, st.v
+			statesMu.Unlock()
+			if upgraded && done && v != nil {
+				// The job for h already ran and decided not to deliver;
+				// with the value already decoded and cached, deliver it
+				// now rather than re-running the job just to resend it.
+				lvs.deliver(out, v, true)
+			}
+			return
+		}
+		states[h] = &readJobState{wanted: wanted}
+		statesMu.Unlock()
+		wg.Add(1)
+		jobs.push(readJob{h, depth, wanted})
+	}
+	finishJob := func(h hash.Hash, v Value) bool {
+		statesMu.Lock()
+		defer statesMu.Unlock()
+		st := states[h]
+		st.done, st.v = true, v
+		return st.wanted
+	}
+
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			for {
+				j, ok := jobs.pop()
+				if !ok {
+					return
+				}
+				lvs.runReadJob(j, opts, out, enqueue, finishJob)
+				wg.Done()
+			}
+		}()
+	}
+
+	// Seed synchronously, in this goroutine, so every wg.Add happens-before
+	// the wg.Wait below -- seeding from a separate goroutine raced Wait
+	// against the first Add and could return immediately with zero jobs
+	// issued.
+	for h := range hashes {
+		enqueue(h, 0, true)
+	}
+	wg.Wait()
+	jobs.close()
+}
+
+func (lvs *ValueStore) runReadJob(j readJob, opts ReadOptions, out chan<- Value, enqueue func(hash.Hash, int, bool), finishJob func(hash.Hash, Value) bool) {
+	lvs.mu.Lock()
+	if v, ok := lvs.pendingPuts[j.h]; ok {
+		lvs.mu.Unlock()
+		lvs.deliver(out, v, finishJob(j.h, v))
+		lvs.prefetch(v, j.depth, opts, enqueue)
+		return
+	}
+	if v, ok := lvs.decodedCache.get(j.h); ok {
+		lvs.mu.Unlock()
+		lvs.deliver(out, v.(Value), finishJob(j.h, v.(Value)))
+		lvs.prefetch(v.(Value), j.depth, opts, enqueue)
+		return
+	}
+	if raw, ok := lvs.rawCache.get(j.h); ok {
+		lvs.mu.Unlock()
+		v := decodeChunk(raw.(chunks.Chunk))
+		lvs.mu.Lock()
+		lvs.cacheDecoded(j.h, v)
+		lvs.mu.Unlock()
+		lvs.deliver(out, v, finishJob(j.h, v))
+		lvs.prefetch(v, j.depth, opts, enqueue)
+		return
+	}
+	lvs.checkVersion()
+	lvs.mu.Unlock()
+
+	c := lvs.cs.Get(j.h)
+	if c.IsEmpty() {
+		finishJob(j.h, nil)
+		return
+	}
+	v := decodeChunk(c)
+	lvs.mu.Lock()
+	lvs.cacheRaw(c)
+	lvs.cacheDecoded(j.h, v)
+	lvs.mu.Unlock()
+	lvs.deliver(out, v, finishJob(j.h, v))
+	lvs.prefetch(v, j.depth, opts, enqueue)
+}
+
+func (lvs *ValueStore) deliver(out chan<- Value, v Value, wanted bool) {
+	if wanted {
+		out <- v
+	}
+}
+
+func (lvs *ValueStore) prefetch(v Value, depth int, opts ReadOptions, enqueue func(hash.Hash, int, bool)) {
+	if depth >= opts.PrefetchDepth {
+		return
+	}
+	v.WalkRefs(func(r Ref) {
+		enqueue(r.TargetHash(), depth+1, false)
+	})
+}
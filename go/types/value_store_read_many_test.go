@@ -0,0 +1,256 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/testify/assert"
+)
+
+// slowChunkStore adds a fixed latency to every Get, standing in for a
+// network-backed ChunkStore, so tests can show that concurrency actually
+// buys wall-clock time rather than just exercising the code path.
+type slowChunkStore struct {
+	chunks.ChunkStore
+	latency time.Duration
+	gets    uint64 // atomic
+}
+
+func (s *slowChunkStore) Get(h hash.Hash) chunks.Chunk {
+	atomic.AddUint64(&s.gets, 1)
+	time.Sleep(s.latency)
+	return s.ChunkStore.Get(h)
+}
+
+func TestReadManyValuesWithOptionsConcurrencySpeedup(t *testing.T) {
+	assert := assert.New(t)
+	const latency = 20 * time.Millisecond
+	const n = 10
+
+	cs := &slowChunkStore{ChunkStore: chunks.NewTestStore(), latency: latency}
+	vs := NewValueStore(cs)
+
+	hashes := hash.HashSet{}
+	for i := 0; i < n; i++ {
+		hashes.Insert(vs.WriteValue(Number(i)).TargetHash())
+	}
+	vs.Flush()
+
+	// Sequential baseline: n ReadValue calls against the slow store, from a
+	// ValueStore with empty caches of its own (vs's caches were already
+	// warmed by the Flush above).
+	vs1 := NewValueStore(cs)
+	start := time.Now()
+	for h := range hashes {
+		assert.NotNil(vs1.ReadValue(h))
+	}
+	sequential := time.Since(start)
+
+	// And again from a second fresh ValueStore, this time concurrently.
+	vs2 := NewValueStore(cs)
+
+	out := make(chan Value, n)
+	start = time.Now()
+	vs2.ReadManyValuesWithOptions(hashes, ReadOptions{Concurrency: n}, out)
+	concurrent := time.Since(start)
+	close(out)
+
+	found := 0
+	for range out {
+		found++
+	}
+	assert.Equal(n, found)
+	assert.True(concurrent < sequential, "concurrent reads (%s) should beat sequential (%s)", concurrent, sequential)
+}
+
+func TestReadManyValuesWithOptionsPrefetchesRefChildren(t *testing.T) {
+	assert := assert.New(t)
+	cs := &slowChunkStore{ChunkStore: chunks.NewTestStore()}
+	vs := NewValueStore(cs)
+
+	leafRef := vs.WriteValue(String("leaf"))
+	rootHash := vs.WriteValue(NewList(leafRef)).TargetHash()
+	vs.Flush()
+
+	fresh := NewValueStore(cs)
+	out := make(chan Value, 1)
+	fresh.ReadManyValuesWithOptions(hash.HashSet{rootHash: struct{}{}}, ReadOptions{Concurrency: 2, PrefetchDepth: 1}, out)
+	close(out)
+	for range out {
+	}
+
+	// The leaf was prefetched as a side effect of reading the root, so it
+	// should now be sitting in the decoded cache without another Get.
+	before := atomic.LoadUint64(&cs.gets)
+	v := fresh.ReadValue(leafRef.TargetHash())
+	after := atomic.LoadUint64(&cs.gets)
+	assert.True(String("leaf").Equals(v))
+	assert.Equal(before, after, "leaf should have been served from cache, not a fresh Get")
+}
+
+func TestReadManyValuesWithOptionsDedupsInFlight(t *testing.T) {
+	assert := assert.New(t)
+	cs := &slowChunkStore{ChunkStore: chunks.NewTestStore(), latency: 5 * time.Millisecond}
+	vs := NewValueStore(cs)
+
+	sharedRef := vs.WriteValue(String("shared"))
+	h := sharedRef.TargetHash()
+	parent := vs.WriteValue(NewList(sharedRef)).TargetHash()
+	vs.Flush()
+
+	fresh := NewValueStore(cs)
+	out := make(chan Value, 4)
+	// Ask for the same hash from both the top-level set and as a synthetic
+	// prefetch target by nesting it one level deep; either way, it must
+	// only be Get once.
+	fresh.ReadManyValuesWithOptions(hash.HashSet{h: struct{}{}, parent: struct{}{}}, ReadOptions{Concurrency: 4, PrefetchDepth: 1}, out)
+	close(out)
+	n := 0
+	for range out {
+		n++
+	}
+	assert.Equal(2, n)
+	assert.Equal(uint64(2), atomic.LoadUint64(&cs.gets), "shared should be Get once, parent once")
+}
+
+// TestReadManyValuesWithOptionsDefaultConcurrencyPrefetchDoesNotDeadlock
+// guards against a worker blocking on a send to itself: with the
+// documented default concurrency of 1 and prefetching on, the sole worker
+// must be able to hand itself a prefetch job without ever blocking.
+func TestReadManyValuesWithOptionsDefaultConcurrencyPrefetchDoesNotDeadlock(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := NewValueStore(cs)
+
+	leafRef := vs.WriteValue(String("leaf"))
+	midRef := vs.WriteValue(NewList(leafRef))
+	rootHash := vs.WriteValue(NewList(midRef)).TargetHash()
+	vs.Flush()
+
+	fresh := NewValueStore(cs)
+	out := make(chan Value, 3)
+
+	done := make(chan struct{})
+	go func() {
+		fresh.ReadManyValuesWithOptions(hash.HashSet{rootHash: struct{}{}}, ReadOptions{PrefetchDepth: 2}, out)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadManyValuesWithOptions deadlocked with default concurrency and prefetching on")
+	}
+	close(out)
+
+	found := 0
+	for range out {
+		found++
+	}
+	assert.Equal(1, found, "only the requested root should be delivered, not its prefetched children")
+}
+
+// TestReadManyValuesWithOptionsWantedSurvivesPrefetchRace guards against a
+// wanted request for a hash being dropped because a worker's prefetch of
+// that same hash (unwanted) raced ahead of the seed loop and claimed it
+// first: since workers start consuming before the seed loop finishes, a
+// worker can discover-and-enqueue a Ref child before the seed loop reaches
+// that same hash in the caller's requested set.
+func TestReadManyValuesWithOptionsWantedSurvivesPrefetchRace(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := NewValueStore(cs)
+
+	const width = 200
+	children := make([]Value, width)
+	childHashes := make([]hash.Hash, width)
+	for i := range children {
+		childRef := vs.WriteValue(Number(i))
+		children[i] = childRef
+		childHashes[i] = childRef.TargetHash()
+	}
+	rootHash := vs.WriteValue(NewList(children...)).TargetHash()
+	vs.Flush()
+
+	// Ask for the root (whose prefetch will discover every child as
+	// unwanted) and every child directly (wanted) in the same call, so any
+	// child whose wanted request loses the race against its own prefetch
+	// discovery would otherwise be silently dropped.
+	fresh := NewValueStore(cs)
+	hashes := hash.HashSet{rootHash: struct{}{}}
+	for _, h := range childHashes {
+		hashes.Insert(h)
+	}
+	out := make(chan Value, width+1)
+	fresh.ReadManyValuesWithOptions(hashes, ReadOptions{Concurrency: 8, PrefetchDepth: 1}, out)
+	close(out)
+
+	found := hash.HashSet{}
+	for v := range out {
+		found.Insert(v.Hash())
+	}
+	assert.Equal(width+1, len(found))
+	for _, h := range childHashes {
+		assert.True(found.Has(h), "child %s requested directly should have been delivered despite being prefetched too", h)
+	}
+	assert.True(found.Has(rootHash))
+}
+
+// TestReadManyValuesWithOptionsPrefetchDoesNotSpawnAGoroutinePerRef guards
+// against fan-out discovered during prefetch being funneled through a
+// goroutine-per-job scheme: with a single worker, a wide parent should
+// never need more than a couple of goroutines outstanding to drain, no
+// matter how many Ref children it has.
+func TestReadManyValuesWithOptionsPrefetchDoesNotSpawnAGoroutinePerRef(t *testing.T) {
+	assert := assert.New(t)
+	cs := chunks.NewTestStore()
+	vs := NewValueStore(cs)
+
+	const width = 2000
+	children := make([]Value, width)
+	for i := range children {
+		children[i] = vs.WriteValue(Number(i))
+	}
+	rootHash := vs.WriteValue(NewList(children...)).TargetHash()
+	vs.Flush()
+
+	fresh := NewValueStore(cs)
+	out := make(chan Value, 1)
+
+	before := runtime.NumGoroutine()
+	peak := int64(before)
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if n := int64(runtime.NumGoroutine()); n > atomic.LoadInt64(&peak) {
+					atomic.StoreInt64(&peak, n)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	fresh.ReadManyValuesWithOptions(hash.HashSet{rootHash: struct{}{}}, ReadOptions{Concurrency: 1, PrefetchDepth: 1}, out)
+	close(stop)
+	close(out)
+	for range out {
+	}
+
+	// A goroutine-per-discovered-ref scheme would add on the order of
+	// `width` goroutines; a bounded worker pool adds a small constant
+	// number regardless of width.
+	finalPeak := atomic.LoadInt64(&peak)
+	assert.True(finalPeak < int64(before)+50, "prefetching %d refs should not spawn a goroutine per ref (before=%d, peak=%d)", width, before, finalPeak)
+}